@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"sync"
+	"time"
+)
+
+// minHeartbeat and maxHeartbeat bound the adaptive interval computed by
+// adaptiveScheduler - no matter how busy or idle the node is, updates never
+// come faster than minHeartbeat or slower than maxHeartbeat.
+const minHeartbeat = 1 * time.Minute
+const maxHeartbeat = 1 * time.Hour
+
+// ewmaAlpha weights how quickly the "interesting delta" average reacts to a
+// new reading; 0.3 favors recent activity without being noisy on a single
+// spike.
+const ewmaAlpha = 0.3
+
+// idleThreshold and activeThreshold gate interval changes on the EWMA's
+// magnitude rather than its bare sign. Without this, the EWMA's geometric
+// decay only asymptotically approaches zero, so a single blip (one peer
+// connecting and disconnecting) would keep the interval pinned at
+// minHeartbeat for a very long time even on an otherwise-idle node, and any
+// nonzero reading at all would halve the interval regardless of how small.
+const idleThreshold = 0.5
+const activeThreshold = 2.0
+
+// adaptiveScheduler replaces the old fixed 15-minute ticker with an interval
+// that tracks an EWMA of how much has changed (peers, bandwidth, exchanges)
+// since the last heartbeat: busy nodes get polled more often, idle ones
+// less, similar to the peer-rate trackers used elsewhere in the p2p stack.
+// Next is called from the collection goroutine; Interval is also read from
+// the stats HTTP handler's goroutine, so access is guarded by mu.
+type adaptiveScheduler struct {
+	mu       sync.Mutex
+	ewma     float64
+	interval time.Duration
+
+	prevNumPeers    uint64
+	prevThroughput  uint64
+	prevExchanges   uint64
+	havePrevReading bool
+}
+
+func newAdaptiveScheduler() *adaptiveScheduler {
+	return &adaptiveScheduler{interval: heartBeat}
+}
+
+// Next folds in the latest reading and returns the interval to wait before
+// the next heartbeat.
+func (a *adaptiveScheduler) Next(numPeers, throughput, exchanges uint64) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.havePrevReading {
+		a.prevNumPeers, a.prevThroughput, a.prevExchanges = numPeers, throughput, exchanges
+		a.havePrevReading = true
+		return a.interval
+	}
+
+	delta := absDeltaU64(numPeers, a.prevNumPeers) +
+		absDeltaU64(throughput, a.prevThroughput) +
+		absDeltaU64(exchanges, a.prevExchanges)
+	a.prevNumPeers, a.prevThroughput, a.prevExchanges = numPeers, throughput, exchanges
+
+	a.ewma = ewmaAlpha*float64(delta) + (1-ewmaAlpha)*a.ewma
+
+	switch {
+	case a.ewma >= activeThreshold:
+		a.interval /= 2
+	case a.ewma < idleThreshold:
+		a.interval *= 2
+	default:
+		// Between the thresholds: neither clearly busy nor clearly idle,
+		// leave the interval where it is.
+	}
+
+	if a.interval < minHeartbeat {
+		a.interval = minHeartbeat
+	}
+	if a.interval > maxHeartbeat {
+		a.interval = maxHeartbeat
+	}
+	return a.interval
+}
+
+// Interval returns the current heartbeat interval, safe to call from any
+// goroutine (e.g. the stats HTTP handler).
+func (a *adaptiveScheduler) Interval() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.interval
+}
+
+func absDeltaU64(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
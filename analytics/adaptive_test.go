@@ -0,0 +1,74 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveSchedulerFirstReadingKeepsBaseInterval(t *testing.T) {
+	s := newAdaptiveScheduler()
+	got := s.Next(1, 100, 1)
+	if got != heartBeat {
+		t.Fatalf("first reading = %v, want base heartbeat %v", got, heartBeat)
+	}
+}
+
+func TestAdaptiveSchedulerShortensOnActivity(t *testing.T) {
+	s := newAdaptiveScheduler()
+	s.Next(0, 0, 0) // seed prev reading
+
+	// A sustained burst of large deltas should push the EWMA over
+	// activeThreshold and shrink the interval below the base.
+	var got time.Duration
+	for i := 0; i < 5; i++ {
+		got = s.Next(uint64(10*(i+1)), uint64(10000*(i+1)), uint64(50*(i+1)))
+	}
+	if got >= heartBeat {
+		t.Fatalf("interval after sustained activity = %v, want < base heartbeat %v", got, heartBeat)
+	}
+	if got < minHeartbeat {
+		t.Fatalf("interval = %v, want >= minHeartbeat %v", got, minHeartbeat)
+	}
+}
+
+func TestAdaptiveSchedulerLengthensWhenIdle(t *testing.T) {
+	s := newAdaptiveScheduler()
+	s.Next(5, 500, 10) // seed prev reading
+
+	// No change at all, tick after tick: the EWMA should decay below
+	// idleThreshold and the interval should grow past the base heartbeat,
+	// eventually saturating at maxHeartbeat.
+	var got time.Duration
+	for i := 0; i < 30; i++ {
+		got = s.Next(5, 500, 10)
+	}
+	if got != maxHeartbeat {
+		t.Fatalf("interval after sustained idleness = %v, want maxHeartbeat %v", got, maxHeartbeat)
+	}
+}
+
+func TestAdaptiveSchedulerSingleBlipDoesNotPinToFloor(t *testing.T) {
+	s := newAdaptiveScheduler()
+	s.Next(5, 500, 10) // seed prev reading
+
+	// One single small blip (a single peer connecting) must not by itself
+	// collapse the interval all the way to minHeartbeat.
+	got := s.Next(6, 500, 10)
+	if got == minHeartbeat {
+		t.Fatalf("single small blip collapsed interval straight to minHeartbeat")
+	}
+}
+
+func TestAbsDeltaU64(t *testing.T) {
+	cases := []struct{ a, b, want uint64 }{
+		{5, 3, 2},
+		{3, 5, 2},
+		{4, 4, 0},
+		{0, 0, 0},
+	}
+	for _, c := range cases {
+		if got := absDeltaU64(c.a, c.b); got != c.want {
+			t.Errorf("absDeltaU64(%d, %d) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -1,11 +1,10 @@
 package analytics
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/TRON-US/go-btfs/core"
@@ -27,6 +26,18 @@ type programInfo struct {
 
 type dataCollection struct {
 	programInfo
+	sinks      []MetricsSink
+	peerStats  *peerStatsTracker
+	lastUpdate time.Time
+	level      AnalyticsLevel
+	scheduler  *adaptiveScheduler
+
+	// statsMu guards consecutiveFailures, which the collection goroutine
+	// writes and the /api/v1/stats/collection handler reads from the HTTP
+	// server's goroutine.
+	statsMu             sync.Mutex
+	consecutiveFailures int
+
 	UpTime      uint64  `json:"up_time"`         //Seconds
 	StorageUsed uint64  `json:"storage_used"`    //Stored in Kilobytes
 	MemUsed     uint64  `json:"memory_used"`     //Stored in Kilobytes
@@ -39,6 +50,16 @@ type dataCollection struct {
 	BlocksDown  uint64  `json:"blocks_down"`     //Total num of blocks downloaded
 	Exchanges   uint64  `json:"exchanges"`       //Number of block exchanges
 	NumPeers    uint64  `json:"peers_connected"` //Number of peers
+
+	// PeerLatencyP* and PeerSpeedP* summarize dc.peerStats across all peers,
+	// so sinks other than /api/v1/stats/peers (prometheus, otlp, log) still
+	// carry per-peer distribution data rather than only connection counts.
+	PeerLatencyP50 float64 `json:"peer_latency_p50_ms"`
+	PeerLatencyP90 float64 `json:"peer_latency_p90_ms"`
+	PeerLatencyP99 float64 `json:"peer_latency_p99_ms"`
+	PeerSpeedP50   float64 `json:"peer_speed_p50_kbs"`
+	PeerSpeedP90   float64 `json:"peer_speed_p90_kbs"`
+	PeerSpeedP99   float64 `json:"peer_speed_p99_kbs"`
 }
 
 const kilobyte = 1024
@@ -49,8 +70,6 @@ var heartBeat = 15 * time.Minute
 //Server URL for data collection
 var dataServeURL = "http://18.220.204.165:8080/metrics"
 
-var peerCountMap map[peer.ID]uint64
-
 //Go doesn't have a built in Max function? simple function to not have negatives values
 func valOrZero(x uint64) uint64 {
 	if x < 0 {
@@ -64,24 +83,75 @@ func durationToSeconds(duration time.Duration) uint64 {
 	return uint64(duration.Nanoseconds() / int64(time.Second/time.Nanosecond))
 }
 
-//Initialize starts the process to collect data and starts the GoRoutine for constant collection
-func Initialize(n *core.IpfsNode, BTFSVersion string) {
+//Initialize starts the process to collect data and starts the GoRoutine for
+//constant collection. sinkCfgs comes from the node's Experimental.Analytics.Sinks
+//config; an empty slice falls back to an http sink pointed at the legacy
+//hard-coded collector URL, so existing installs keep reporting to the same
+//collector until they opt into more sinks.
+//mux, if non-nil, is the node's API mux that sinks exposing a scrape
+//endpoint (e.g. Prometheus) register themselves on. level gates how much of
+//the payload is ever populated; LevelDisabled skips collection altogether.
+//repoPath is where the node-ID anonymization salt is persisted.
+func Initialize(n *core.IpfsNode, BTFSVersion string, sinkCfgs []SinkConfig, mux *http.ServeMux, level AnalyticsLevel, repoPath string) {
+	if level == LevelDisabled {
+		return
+	}
+
 	dc := new(dataCollection)
 	infoStats, _ := cpu.Info()
 
 	dc.node = n
 	dc.startTime = time.Now()
-	dc.NodeID = n.Identity.Pretty()
+	dc.level = level
+
+	salt, err := loadOrCreateSalt(repoPath)
+	if err != nil {
+		fmt.Println("analytics: could not load/create salt, falling back to unsalted ID:", err)
+		dc.NodeID = n.Identity.Pretty()
+	} else {
+		dc.NodeID = anonymizeNodeID(n.Identity.Pretty(), salt)
+	}
+
 	dc.CPUInfo = infoStats[0].ModelName
 	dc.BTFSVersion = BTFSVersion
 	dc.OSType = runtime.GOOS
 	dc.ArchType = runtime.GOARCH
 
-	peerCountMap = make(map[peer.ID]uint64)
+	if len(sinkCfgs) == 0 {
+		sinkCfgs = []SinkConfig{{Type: "http", URL: dataServeURL}}
+	}
+	dc.sinks = BuildSinks(sinkCfgs, repoPath, n.PrivateKey)
+	dc.peerStats = newPeerStatsTracker()
+	dc.scheduler = newAdaptiveScheduler()
+	registerSinkHandlers(mux, dc.sinks)
+	registerStatsHandlers(mux, dc)
 
 	go dc.collectionAgent()
 }
 
+// registerSinkHandlers mounts any sink that exposes a scrape endpoint (such
+// as Prometheus) onto the node's API mux. Sinks are unwrapped first since a
+// configured SampleInterval wraps them in an intervalGatedSink, which would
+// otherwise hide the concrete type from this type assertion.
+func registerSinkHandlers(mux *http.ServeMux, sinks []MetricsSink) {
+	if mux == nil {
+		return
+	}
+	for _, sink := range sinks {
+		for {
+			if p, ok := sink.(*prometheusSink); ok {
+				mux.Handle("/metrics", p.Handler())
+				break
+			}
+			u, ok := sink.(interface{ Unwrap() MetricsSink })
+			if !ok {
+				break
+			}
+			sink = u.Unwrap()
+		}
+	}
+}
+
 func (dc *dataCollection) update() {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -110,9 +180,22 @@ func (dc *dataCollection) update() {
 	dc.BlocksUp = st.BlocksSent
 	dc.BlocksDown = st.BlocksReceived
 
-	//This iterates over all peers connected on ledger, this might end up being prohibitively
-	//expensive in the future. Better would be maintain a counter like the other stats
+	//This iterates over all peers connected on ledger. The per-peer exchange
+	//counter lives on peerStats' own bounded, LRU-evicting record now (see
+	//ExchangeDelta) instead of a second, unbounded map.
+	now := time.Now()
+	elapsedMs := float64(now.Sub(dc.lastUpdate)) / float64(time.Millisecond)
+	if dc.lastUpdate.IsZero() {
+		elapsedMs = float64(heartBeat / time.Millisecond)
+	}
+
 	var exchangeCount uint64
+	type peerDelta struct {
+		id    peer.ID
+		delta uint64
+	}
+	deltas := make([]peerDelta, 0, len(st.Peers))
+	connected := make(map[peer.ID]bool, len(st.Peers))
 	for _, peerString := range st.Peers {
 		peerID, err := peer.IDB58Decode(peerString)
 		if err != nil {
@@ -120,40 +203,82 @@ func (dc *dataCollection) update() {
 			return
 		}
 		peerRec := bs.LedgerForPeer(peerID)
-		exchangeCount += peerRec.Exchanged - peerCountMap[peerID]
-		peerCountMap[peerID] = peerRec.Exchanged
+		delta := dc.peerStats.ExchangeDelta(peerID, peerRec.Exchanged)
+		exchangeCount += delta
+		deltas = append(deltas, peerDelta{peerID, delta})
+		connected[peerID] = true
+	}
+	dc.peerStats.EvictStale(connected)
+
+	//Apportion this epoch's total transfer across peers by their share of
+	//exchanges, and derive a per-exchange latency from the elapsed interval.
+	//This is an approximation: it records distribution trends, not individual
+	//request timings, since bitswap's ledger doesn't expose per-exchange
+	//latency directly.
+	totalBytes := float64(dc.Upload+dc.Download) * kilobyte
+	for _, pd := range deltas {
+		if pd.delta == 0 {
+			continue
+		}
+		share := float64(pd.delta) / float64(exchangeCount)
+		speedBytesPerMs := (totalBytes * share) / elapsedMs
+		latency := time.Duration(elapsedMs/float64(pd.delta)) * time.Millisecond
+		blocksUp := uint64(float64(dc.BlocksUp) * share)
+		blocksDown := uint64(float64(dc.BlocksDown) * share)
+		dc.peerStats.RecordSample(pd.id, latency, speedBytesPerMs, blocksUp, blocksDown)
 	}
 
 	dc.Exchanges = exchangeCount
 	dc.NumPeers = uint64(len(st.Peers))
+	dc.lastUpdate = now
+
+	agg := dc.peerStats.Aggregate()
+	dc.PeerLatencyP50 = agg.LatencyP50
+	dc.PeerLatencyP90 = agg.LatencyP90
+	dc.PeerLatencyP99 = agg.LatencyP99
+	dc.PeerSpeedP50 = agg.SpeedP50
+	dc.PeerSpeedP90 = agg.SpeedP90
+	dc.PeerSpeedP99 = agg.SpeedP99
 }
 
 func (dc *dataCollection) sendData() {
 	dc.update()
-	temp, _ := json.Marshal(dc)
+	payload := dc.redactForLevel()
 
-	req, err := http.NewRequest("POST", dataServeURL, bytes.NewReader(temp))
-	req.Header.Add("Content-Type", "application/json")
-	if err != nil {
-		return
+	ok := true
+	for _, sink := range dc.sinks {
+		if err := sink.Send(payload); err != nil {
+			fmt.Println("analytics: sink", sink.Name(), "failed:", err)
+			ok = false
+		}
 	}
 
-	res, err := http.DefaultClient.Do(req)
-
-	if err != nil {
-		return
+	dc.statsMu.Lock()
+	if ok {
+		dc.consecutiveFailures = 0
+	} else {
+		dc.consecutiveFailures++
 	}
+	dc.statsMu.Unlock()
+}
 
-	defer res.Body.Close()
+// ConsecutiveFailures reports how many heartbeats in a row at least one sink
+// has failed on, safe to call from any goroutine (e.g. the stats HTTP handler).
+func (dc *dataCollection) ConsecutiveFailures() int {
+	dc.statsMu.Lock()
+	defer dc.statsMu.Unlock()
+	return dc.consecutiveFailures
 }
 
+//collectionAgent drives the heartbeat loop. The base interval adapts to how
+//much is changing (peer churn, throughput, exchanges) via dc.scheduler,
+//jitters around that base so many nodes started together don't all report
+//in lockstep, and backs off exponentially (capped at maxBackoff) while sinks
+//keep failing.
 func (dc *dataCollection) collectionAgent() {
-	tick := time.NewTicker(heartBeat)
-
-	defer tick.Stop()
-	dc.sendData()
-
-	for range tick.C {
+	for {
 		dc.sendData()
+		base := dc.scheduler.Next(dc.NumPeers, dc.Upload+dc.Download, dc.Exchanges)
+		time.Sleep(nextBackoff(base, dc.ConsecutiveFailures()))
 	}
-}
\ No newline at end of file
+}
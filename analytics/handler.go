@@ -0,0 +1,44 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// registerStatsHandlers mounts the analytics HTTP endpoints (peer stats,
+// and anything added later) onto the node's API mux.
+func registerStatsHandlers(mux *http.ServeMux, dc *dataCollection) {
+	if mux == nil {
+		return
+	}
+	mux.HandleFunc("/api/v1/stats/peers", dc.peerStatsHandler)
+	mux.HandleFunc("/api/v1/stats/collection", dc.collectionStatsHandler)
+}
+
+// peerStatsHandler serves the current per-peer latency/speed distribution
+// as a JSON array of PeerStatsRecord.
+func (dc *dataCollection) peerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dc.peerStats.Snapshot()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// collectionStatsRecord reports the collection agent's own behavior, mainly
+// so operators can see the adaptive scheduler's current interval without
+// digging through logs.
+type collectionStatsRecord struct {
+	CurrentIntervalSeconds float64 `json:"current_interval_seconds"`
+	ConsecutiveFailures    int     `json:"consecutive_failures"`
+}
+
+func (dc *dataCollection) collectionStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	rec := collectionStatsRecord{
+		CurrentIntervalSeconds: dc.scheduler.Interval().Seconds(),
+		ConsecutiveFailures:    dc.ConsecutiveFailures(),
+	}
+	if err := json.NewEncoder(w).Encode(rec); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
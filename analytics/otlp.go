@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+)
+
+// otlpCollectPeriod is how often the controller flushes accumulated
+// recordings to the exporter, independent of how often Send is called.
+const otlpCollectPeriod = 10 * time.Second
+
+// otlpSink exports each heartbeat as an OTLP metrics payload over gRPC or
+// HTTP, depending on the configured URL scheme, so operators can feed BTFS
+// telemetry into any OpenTelemetry-compatible collector.
+type otlpSink struct {
+	url        string
+	exporter   export.Exporter
+	controller *controller.Controller
+
+	upTime      metric.Float64ValueRecorder
+	storageUsed metric.Float64ValueRecorder
+	memUsed     metric.Float64ValueRecorder
+	cpuUsed     metric.Float64ValueRecorder
+	upload      metric.Float64ValueRecorder
+	download    metric.Float64ValueRecorder
+	numPeers    metric.Float64ValueRecorder
+
+	peerLatencyP50 metric.Float64ValueRecorder
+	peerLatencyP90 metric.Float64ValueRecorder
+	peerLatencyP99 metric.Float64ValueRecorder
+	peerSpeedP50   metric.Float64ValueRecorder
+	peerSpeedP90   metric.Float64ValueRecorder
+	peerSpeedP99   metric.Float64ValueRecorder
+}
+
+func newOTLPSink(c SinkConfig) (*otlpSink, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("otlp sink requires a url")
+	}
+
+	var exporter export.Exporter
+	var err error
+	switch {
+	case isGRPCEndpoint(c.URL):
+		exporter, err = otlpmetricgrpc.New(context.Background(), otlpmetricgrpc.WithEndpoint(c.URL))
+	default:
+		exporter, err = otlpmetrichttp.New(context.Background(), otlpmetrichttp.WithEndpoint(c.URL))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("otlp sink: %w", err)
+	}
+
+	// The exporter only ever gets invoked by a controller that periodically
+	// collects and pushes; a bare MeterProvider records in memory and never
+	// forwards anything to it.
+	cont := controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(), exporter),
+		controller.WithExporter(exporter),
+		controller.WithCollectPeriod(otlpCollectPeriod),
+	)
+	if err := cont.Start(context.Background()); err != nil {
+		return nil, fmt.Errorf("otlp sink: starting controller: %w", err)
+	}
+
+	meter := cont.Meter("btfs.analytics")
+	return &otlpSink{
+		url:         c.URL,
+		exporter:    exporter,
+		controller:  cont,
+		upTime:      mustFloat64Recorder(meter, "btfs.analytics.up_time_seconds"),
+		storageUsed: mustFloat64Recorder(meter, "btfs.analytics.storage_used_kilobytes"),
+		memUsed:     mustFloat64Recorder(meter, "btfs.analytics.memory_used_kilobytes"),
+		cpuUsed:     mustFloat64Recorder(meter, "btfs.analytics.cpu_used_percent"),
+		upload:      mustFloat64Recorder(meter, "btfs.analytics.upload_kilobytes"),
+		download:    mustFloat64Recorder(meter, "btfs.analytics.download_kilobytes"),
+		numPeers:    mustFloat64Recorder(meter, "btfs.analytics.peers_connected"),
+
+		peerLatencyP50: mustFloat64Recorder(meter, "btfs.analytics.peer_latency_p50_ms"),
+		peerLatencyP90: mustFloat64Recorder(meter, "btfs.analytics.peer_latency_p90_ms"),
+		peerLatencyP99: mustFloat64Recorder(meter, "btfs.analytics.peer_latency_p99_ms"),
+		peerSpeedP50:   mustFloat64Recorder(meter, "btfs.analytics.peer_speed_p50_kbs"),
+		peerSpeedP90:   mustFloat64Recorder(meter, "btfs.analytics.peer_speed_p90_kbs"),
+		peerSpeedP99:   mustFloat64Recorder(meter, "btfs.analytics.peer_speed_p99_kbs"),
+	}, nil
+}
+
+func mustFloat64Recorder(meter metric.Meter, name string) metric.Float64ValueRecorder {
+	rec, err := meter.NewFloat64ValueRecorder(name)
+	if err != nil {
+		panic(err)
+	}
+	return rec
+}
+
+func isGRPCEndpoint(url string) bool {
+	return len(url) >= 4 && url[:4] != "http"
+}
+
+func (o *otlpSink) Name() string { return "otlp:" + o.url }
+
+func (o *otlpSink) Send(dc *dataCollection) error {
+	ctx := context.Background()
+	o.upTime.Record(ctx, float64(dc.UpTime))
+	o.storageUsed.Record(ctx, float64(dc.StorageUsed))
+	o.memUsed.Record(ctx, float64(dc.MemUsed))
+	o.cpuUsed.Record(ctx, dc.CPUUsed)
+	o.upload.Record(ctx, float64(dc.Upload))
+	o.download.Record(ctx, float64(dc.Download))
+	o.numPeers.Record(ctx, float64(dc.NumPeers))
+	o.peerLatencyP50.Record(ctx, dc.PeerLatencyP50)
+	o.peerLatencyP90.Record(ctx, dc.PeerLatencyP90)
+	o.peerLatencyP99.Record(ctx, dc.PeerLatencyP99)
+	o.peerSpeedP50.Record(ctx, dc.PeerSpeedP50)
+	o.peerSpeedP90.Record(ctx, dc.PeerSpeedP90)
+	o.peerSpeedP99.Record(ctx, dc.PeerSpeedP99)
+	return nil
+}
+
+func (o *otlpSink) Close() error {
+	return o.controller.Stop(context.Background())
+}
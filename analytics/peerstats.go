@@ -0,0 +1,243 @@
+package analytics
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/influxdata/tdigest"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// maxTrackedPeers bounds the per-peer stats map. The old peerCountMap grew
+// forever (see the TODO that used to live in update()) - that counter now
+// lives on peerRecord itself, so there's a single bounded per-peer map
+// instead of two. We LRU-evict the least-recently-updated peer once the map
+// is full or update() reports the peer is no longer connected.
+const maxTrackedPeers = 4096
+
+// Latency and speed bucket boundaries used for the coarse histograms kept
+// on each peerRecord. The bucket counts are not exposed: Snapshot, Aggregate
+// and the sinks report only the t-digest percentile estimates.
+var latencyBucketsMs = []float64{50, 200, 500, 1000}
+var speedBucketsKBs = []float64{100, 1000, 10000}
+
+// peerRecord is the bounded per-peer accumulator: a t-digest per metric for
+// cheap streaming percentile estimates, plus fixed-bucket counts for a
+// quick-glance distribution.
+type peerRecord struct {
+	samples uint64
+
+	// exchanged is the last cumulative bitswap ledger value seen for this
+	// peer, used to turn Ledger.Exchanged into a per-tick delta the way the
+	// old package-level peerCountMap did.
+	exchanged uint64
+
+	latencyDigest *tdigest.TDigest
+	speedDigest   *tdigest.TDigest
+
+	latencyBuckets []uint64
+	speedBuckets   []uint64
+
+	blocksUp   uint64
+	blocksDown uint64
+
+	elem *list.Element // position in the LRU list
+}
+
+// PeerStatsRecord is the JSON shape returned by /api/v1/stats/peers.
+type PeerStatsRecord struct {
+	PeerID     string  `json:"peer_id"`
+	Samples    uint64  `json:"samples"`
+	LatencyP50 float64 `json:"latency_p50"`
+	LatencyP90 float64 `json:"latency_p90"`
+	LatencyP99 float64 `json:"latency_p99"`
+	SpeedP50   float64 `json:"speed_p50"`
+	SpeedP90   float64 `json:"speed_p90"`
+	SpeedP99   float64 `json:"speed_p99"`
+	BlocksUp   uint64  `json:"blocks_up"`
+	BlocksDown uint64  `json:"blocks_down"`
+}
+
+// peerStatsTracker is a bounded, LRU-evicting map from peer ID to
+// peerRecord. It is safe for concurrent use since samples arrive from the
+// collection loop while the stats HTTP handler reads it from another
+// goroutine.
+type peerStatsTracker struct {
+	mu      sync.Mutex
+	records map[peer.ID]*peerRecord
+	lru     *list.List // front = most recently touched
+}
+
+func newPeerStatsTracker() *peerStatsTracker {
+	return &peerStatsTracker{
+		records: make(map[peer.ID]*peerRecord),
+		lru:     list.New(),
+	}
+}
+
+// getOrCreateLocked returns the peer's record, creating it (and evicting the
+// LRU tail if that pushes the map over maxTrackedPeers) if this is the first
+// time id has been seen. Must be called with mu held.
+func (t *peerStatsTracker) getOrCreateLocked(id peer.ID) *peerRecord {
+	rec, ok := t.records[id]
+	if ok {
+		t.lru.MoveToFront(rec.elem)
+		return rec
+	}
+
+	rec = &peerRecord{
+		latencyDigest:  tdigest.New(),
+		speedDigest:    tdigest.New(),
+		latencyBuckets: make([]uint64, len(latencyBucketsMs)+1),
+		speedBuckets:   make([]uint64, len(speedBucketsKBs)+1),
+	}
+	rec.elem = t.lru.PushFront(id)
+	t.records[id] = rec
+	t.evictIfFull()
+	return rec
+}
+
+// ExchangeDelta folds the package's old peerCountMap into the same bounded,
+// LRU-evicting map as the latency/speed histograms: it turns bitswap's
+// cumulative per-peer Ledger.Exchanged counter into a delta since the last
+// call, tracking the previous value on the peer's own record instead of in
+// a second, unbounded map.
+func (t *peerStatsTracker) ExchangeDelta(id peer.ID, currentExchanged uint64) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec := t.getOrCreateLocked(id)
+	delta := currentExchanged - rec.exchanged
+	rec.exchanged = currentExchanged
+	return delta
+}
+
+// RecordSample adds one latency/speed observation for peerID, mirroring how
+// content-routing fetchers log per-request latency (ms) and speed
+// (bytes/ms).
+func (t *peerStatsTracker) RecordSample(id peer.ID, latency time.Duration, speedBytesPerMs float64, blocksUp, blocksDown uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec := t.getOrCreateLocked(id)
+
+	latencyMs := float64(latency) / float64(time.Millisecond)
+	speedKBs := speedBytesPerMs * 1000 / kilobyte
+
+	rec.samples++
+	rec.latencyDigest.Add(latencyMs, 1)
+	rec.speedDigest.Add(speedKBs, 1)
+	bucketInc(rec.latencyBuckets, latencyBucketsMs, latencyMs)
+	bucketInc(rec.speedBuckets, speedBucketsKBs, speedKBs)
+	rec.blocksUp += blocksUp
+	rec.blocksDown += blocksDown
+}
+
+// evictLocked removes a peer's stats immediately. Must be called with mu held.
+func (t *peerStatsTracker) evictLocked(id peer.ID) {
+	rec, ok := t.records[id]
+	if !ok {
+		return
+	}
+	t.lru.Remove(rec.elem)
+	delete(t.records, id)
+}
+
+// EvictStale drops every tracked peer not present in current, called once
+// per update() with the peer set bitswap currently reports as connected -
+// this is the "or disconnect" half of the bound: a peer that drops off
+// bitswap's ledger stops taking up space here on the very next tick instead
+// of only when the LRU limit is eventually hit.
+func (t *peerStatsTracker) EvictStale(current map[peer.ID]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id := range t.records {
+		if !current[id] {
+			t.evictLocked(id)
+		}
+	}
+}
+
+// evictIfFull must be called with mu held.
+func (t *peerStatsTracker) evictIfFull() {
+	for len(t.records) > maxTrackedPeers {
+		oldest := t.lru.Back()
+		if oldest == nil {
+			return
+		}
+		id := oldest.Value.(peer.ID)
+		t.lru.Remove(oldest)
+		delete(t.records, id)
+	}
+}
+
+// Snapshot returns one PeerStatsRecord per currently-tracked peer.
+func (t *peerStatsTracker) Snapshot() []PeerStatsRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]PeerStatsRecord, 0, len(t.records))
+	for id, rec := range t.records {
+		out = append(out, PeerStatsRecord{
+			PeerID:     id.Pretty(),
+			Samples:    rec.samples,
+			LatencyP50: rec.latencyDigest.Quantile(0.5),
+			LatencyP90: rec.latencyDigest.Quantile(0.9),
+			LatencyP99: rec.latencyDigest.Quantile(0.99),
+			SpeedP50:   rec.speedDigest.Quantile(0.5),
+			SpeedP90:   rec.speedDigest.Quantile(0.9),
+			SpeedP99:   rec.speedDigest.Quantile(0.99),
+			BlocksUp:   rec.blocksUp,
+			BlocksDown: rec.blocksDown,
+		})
+	}
+	return out
+}
+
+// AggregateStats summarizes every tracked peer's latency/speed digests into
+// one set of percentiles, for sinks (prometheus, otlp, log) that report a
+// single heartbeat rather than a per-peer breakdown like
+// /api/v1/stats/peers does.
+type AggregateStats struct {
+	LatencyP50 float64
+	LatencyP90 float64
+	LatencyP99 float64
+	SpeedP50   float64
+	SpeedP90   float64
+	SpeedP99   float64
+}
+
+// Aggregate merges every tracked peer's digests into one and returns its
+// percentiles. Safe to call concurrently with RecordSample/EvictStale.
+func (t *peerStatsTracker) Aggregate() AggregateStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	latency := tdigest.New()
+	speed := tdigest.New()
+	for _, rec := range t.records {
+		latency.Merge(rec.latencyDigest)
+		speed.Merge(rec.speedDigest)
+	}
+
+	return AggregateStats{
+		LatencyP50: latency.Quantile(0.5),
+		LatencyP90: latency.Quantile(0.9),
+		LatencyP99: latency.Quantile(0.99),
+		SpeedP50:   speed.Quantile(0.5),
+		SpeedP90:   speed.Quantile(0.9),
+		SpeedP99:   speed.Quantile(0.99),
+	}
+}
+
+func bucketInc(buckets []uint64, bounds []float64, v float64) {
+	for i, b := range bounds {
+		if v < b {
+			buckets[i]++
+			return
+		}
+	}
+	buckets[len(bounds)]++
+}
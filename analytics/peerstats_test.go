@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+func TestPeerStatsTrackerRecordAndSnapshot(t *testing.T) {
+	tr := newPeerStatsTracker()
+	id := peer.ID("peer-a")
+
+	tr.RecordSample(id, 25*time.Millisecond, 50, 1, 2)
+	tr.RecordSample(id, 75*time.Millisecond, 150, 3, 4)
+
+	snap := tr.Snapshot()
+	if len(snap) != 1 {
+		t.Fatalf("Snapshot() returned %d records, want 1", len(snap))
+	}
+	rec := snap[0]
+	if rec.PeerID != id.Pretty() {
+		t.Fatalf("PeerID = %q, want %q", rec.PeerID, id.Pretty())
+	}
+	if rec.Samples != 2 {
+		t.Fatalf("Samples = %d, want 2", rec.Samples)
+	}
+	if rec.BlocksUp != 4 || rec.BlocksDown != 6 {
+		t.Fatalf("BlocksUp/BlocksDown = %d/%d, want 4/6", rec.BlocksUp, rec.BlocksDown)
+	}
+}
+
+func TestPeerStatsTrackerEvictsOldestWhenFull(t *testing.T) {
+	tr := newPeerStatsTracker()
+
+	for i := 0; i < maxTrackedPeers+10; i++ {
+		id := peer.ID(fmt.Sprintf("peer-%d", i))
+		tr.RecordSample(id, time.Millisecond, 10, 0, 0)
+		if len(tr.records) > maxTrackedPeers {
+			t.Fatalf("tracker grew to %d records after peer %d, want capped at %d", len(tr.records), i, maxTrackedPeers)
+		}
+	}
+	if len(tr.records) != maxTrackedPeers {
+		t.Fatalf("final tracker size = %d, want %d", len(tr.records), maxTrackedPeers)
+	}
+
+	if _, ok := tr.records[peer.ID("peer-0")]; ok {
+		t.Fatal("oldest peer should have been LRU-evicted")
+	}
+	newest := peer.ID(fmt.Sprintf("peer-%d", maxTrackedPeers+9))
+	if _, ok := tr.records[newest]; !ok {
+		t.Fatal("most recently recorded peer should still be tracked")
+	}
+}
+
+func TestPeerStatsTrackerEvictStaleRemovesDisconnectedPeers(t *testing.T) {
+	tr := newPeerStatsTracker()
+	a, b := peer.ID("peer-a"), peer.ID("peer-b")
+	tr.RecordSample(a, time.Millisecond, 10, 0, 0)
+	tr.RecordSample(b, time.Millisecond, 10, 0, 0)
+
+	tr.EvictStale(map[peer.ID]bool{a: true})
+
+	if _, ok := tr.records[a]; !ok {
+		t.Fatal("peer present in the current set must not be evicted")
+	}
+	if _, ok := tr.records[b]; ok {
+		t.Fatal("peer absent from the current set must be evicted")
+	}
+}
+
+func TestPeerStatsTrackerExchangeDelta(t *testing.T) {
+	tr := newPeerStatsTracker()
+	id := peer.ID("peer-a")
+
+	if got := tr.ExchangeDelta(id, 5); got != 5 {
+		t.Fatalf("first ExchangeDelta = %d, want 5 (from zero baseline)", got)
+	}
+	if got := tr.ExchangeDelta(id, 8); got != 3 {
+		t.Fatalf("second ExchangeDelta = %d, want 3", got)
+	}
+}
@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// AnalyticsLevel controls how much of the heartbeat payload is actually
+// sent, so operators can opt into aggregate telemetry without handing over
+// more than they're comfortable with.
+type AnalyticsLevel int
+
+const (
+	// LevelDisabled turns analytics off entirely; collectionAgent never runs.
+	LevelDisabled AnalyticsLevel = iota
+	// LevelMinimal reports only the anonymized node ID and version/OS/arch -
+	// enough for adoption counts.
+	LevelMinimal
+	// LevelStandard adds resource usage (uptime, storage, memory, CPU).
+	LevelStandard
+	// LevelFull matches the original payload (resource usage plus bandwidth
+	// and peer counters) and adds the peer latency/speed percentiles.
+	LevelFull
+)
+
+func (l AnalyticsLevel) String() string {
+	switch l {
+	case LevelDisabled:
+		return "disabled"
+	case LevelMinimal:
+		return "minimal"
+	case LevelStandard:
+		return "standard"
+	case LevelFull:
+		return "full"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAnalyticsLevel maps the BTFS config file's string value onto an
+// AnalyticsLevel, defaulting unrecognized values to LevelDisabled so a typo
+// in the config can't accidentally leak more than intended.
+func ParseAnalyticsLevel(s string) (AnalyticsLevel, error) {
+	switch s {
+	case "", "disabled":
+		return LevelDisabled, nil
+	case "minimal":
+		return LevelMinimal, nil
+	case "standard":
+		return LevelStandard, nil
+	case "full":
+		return LevelFull, nil
+	default:
+		return LevelDisabled, fmt.Errorf("unknown analytics level %q", s)
+	}
+}
+
+// redactForLevel returns a copy of dc with every field the configured level
+// doesn't allow zeroed out, so a sink can never observe more than the
+// operator agreed to send.
+func (dc *dataCollection) redactForLevel() *dataCollection {
+	// Built field-by-field rather than via `out := *dc`: dataCollection now
+	// carries a sync.Mutex (statsMu), and copying the struct would copy the
+	// lock value too. None of the unexported bookkeeping fields (sinks,
+	// peerStats, scheduler, statsMu) are part of the JSON payload a sink
+	// sees, so out is left zero-valued for those.
+	out := &dataCollection{
+		programInfo: dc.programInfo,
+		UpTime:      dc.UpTime,
+		StorageUsed: dc.StorageUsed,
+		MemUsed:     dc.MemUsed,
+		CPUUsed:     dc.CPUUsed,
+		Upload:      dc.Upload,
+		Download:    dc.Download,
+		TotalUp:     dc.TotalUp,
+		TotalDown:   dc.TotalDown,
+		BlocksUp:    dc.BlocksUp,
+		BlocksDown:  dc.BlocksDown,
+		Exchanges:   dc.Exchanges,
+		NumPeers:    dc.NumPeers,
+
+		PeerLatencyP50: dc.PeerLatencyP50,
+		PeerLatencyP90: dc.PeerLatencyP90,
+		PeerLatencyP99: dc.PeerLatencyP99,
+		PeerSpeedP50:   dc.PeerSpeedP50,
+		PeerSpeedP90:   dc.PeerSpeedP90,
+		PeerSpeedP99:   dc.PeerSpeedP99,
+	}
+
+	if dc.level < LevelStandard {
+		out.UpTime = 0
+		out.StorageUsed = 0
+		out.MemUsed = 0
+		out.CPUUsed = 0
+		out.CPUInfo = ""
+	}
+	if dc.level < LevelFull {
+		out.Upload = 0
+		out.Download = 0
+		out.TotalUp = 0
+		out.TotalDown = 0
+		out.BlocksUp = 0
+		out.BlocksDown = 0
+		out.Exchanges = 0
+		out.NumPeers = 0
+		out.PeerLatencyP50 = 0
+		out.PeerLatencyP90 = 0
+		out.PeerLatencyP99 = 0
+		out.PeerSpeedP50 = 0
+		out.PeerSpeedP90 = 0
+		out.PeerSpeedP99 = 0
+	}
+	return out
+}
+
+const saltFileName = "analytics-salt"
+const saltSize = 32
+
+// loadOrCreateSalt reads the per-repo salt used to anonymize the node ID,
+// generating and persisting a new one on first run so the anonymized ID
+// stays stable across restarts but can't be reversed to the real peer ID
+// without it.
+func loadOrCreateSalt(repoPath string) ([]byte, error) {
+	path := filepath.Join(repoPath, saltFileName)
+
+	if salt, err := ioutil.ReadFile(path); err == nil && len(salt) == saltSize {
+		return salt, nil
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, salt, 0600); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// anonymizeNodeID derives a stable report identifier from the real libp2p
+// peer ID without exposing it: a salted HMAC-SHA256, so two reports from the
+// same node correlate with each other but not with the node's public
+// identity.
+func anonymizeNodeID(realID string, salt []byte) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(realID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
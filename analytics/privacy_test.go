@@ -0,0 +1,101 @@
+package analytics
+
+import "testing"
+
+func TestParseAnalyticsLevel(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    AnalyticsLevel
+		wantErr bool
+	}{
+		{"", LevelDisabled, false},
+		{"disabled", LevelDisabled, false},
+		{"minimal", LevelMinimal, false},
+		{"standard", LevelStandard, false},
+		{"full", LevelFull, false},
+		{"bogus", LevelDisabled, true},
+	}
+
+	for _, c := range cases {
+		got, err := ParseAnalyticsLevel(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseAnalyticsLevel(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+		}
+		if got != c.want {
+			t.Errorf("ParseAnalyticsLevel(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestAnonymizeNodeID(t *testing.T) {
+	saltA := []byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	saltB := []byte("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+
+	idA := anonymizeNodeID("peer-1", saltA)
+	idA2 := anonymizeNodeID("peer-1", saltA)
+	if idA != idA2 {
+		t.Fatalf("anonymizeNodeID is not deterministic: %q != %q", idA, idA2)
+	}
+
+	if anonymizeNodeID("peer-2", saltA) == idA {
+		t.Fatal("different peer IDs produced the same anonymized ID")
+	}
+	if anonymizeNodeID("peer-1", saltB) == idA {
+		t.Fatal("different salts produced the same anonymized ID")
+	}
+	if idA == "peer-1" {
+		t.Fatal("anonymized ID must not equal the real peer ID")
+	}
+}
+
+func TestRedactForLevel(t *testing.T) {
+	full := func(level AnalyticsLevel) *dataCollection {
+		return &dataCollection{
+			level: level,
+			programInfo: programInfo{
+				BTFSVersion: "v1.2.3",
+				OSType:      "linux",
+				ArchType:    "amd64",
+				CPUInfo:     "Some CPU",
+			},
+			UpTime:      100,
+			StorageUsed: 200,
+			MemUsed:     300,
+			CPUUsed:     12.5,
+			Upload:      10,
+			Download:    20,
+			Exchanges:   5,
+			NumPeers:    3,
+		}
+	}
+
+	t.Run("minimal only keeps version/OS/arch", func(t *testing.T) {
+		out := full(LevelMinimal).redactForLevel()
+		if out.BTFSVersion != "v1.2.3" || out.OSType != "linux" || out.ArchType != "amd64" {
+			t.Fatal("minimal level must still report version/OS/arch")
+		}
+		if out.CPUInfo != "" || out.UpTime != 0 || out.StorageUsed != 0 || out.MemUsed != 0 || out.CPUUsed != 0 {
+			t.Fatal("minimal level must not report resource usage")
+		}
+		if out.Upload != 0 || out.Download != 0 || out.Exchanges != 0 || out.NumPeers != 0 {
+			t.Fatal("minimal level must not report bandwidth/peer data")
+		}
+	})
+
+	t.Run("standard adds resource usage but not bandwidth", func(t *testing.T) {
+		out := full(LevelStandard).redactForLevel()
+		if out.UpTime == 0 || out.StorageUsed == 0 || out.MemUsed == 0 || out.CPUUsed == 0 {
+			t.Fatal("standard level must report resource usage")
+		}
+		if out.Upload != 0 || out.Download != 0 || out.Exchanges != 0 || out.NumPeers != 0 {
+			t.Fatal("standard level must not report bandwidth/peer data")
+		}
+	})
+
+	t.Run("full keeps everything", func(t *testing.T) {
+		out := full(LevelFull).redactForLevel()
+		if out.Upload == 0 || out.Download == 0 || out.Exchanges == 0 || out.NumPeers == 0 {
+			t.Fatal("full level must report bandwidth/peer data")
+		}
+	})
+}
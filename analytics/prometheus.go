@@ -0,0 +1,99 @@
+package analytics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusSink exposes the latest heartbeat as a set of gauges on a local
+// /metrics scrape endpoint rather than pushing anywhere. Send just updates
+// the gauges; a remote Prometheus server is expected to pull from Handler.
+type prometheusSink struct {
+	registry *prometheus.Registry
+
+	upTime      prometheus.Gauge
+	storageUsed prometheus.Gauge
+	memUsed     prometheus.Gauge
+	cpuUsed     prometheus.Gauge
+	upload      prometheus.Gauge
+	download    prometheus.Gauge
+	blocksUp    prometheus.Gauge
+	blocksDown  prometheus.Gauge
+	exchanges   prometheus.Gauge
+	numPeers    prometheus.Gauge
+
+	peerLatencyP50 prometheus.Gauge
+	peerLatencyP90 prometheus.Gauge
+	peerLatencyP99 prometheus.Gauge
+	peerSpeedP50   prometheus.Gauge
+	peerSpeedP90   prometheus.Gauge
+	peerSpeedP99   prometheus.Gauge
+}
+
+func newPrometheusSink(c SinkConfig) *prometheusSink {
+	registry := prometheus.NewRegistry()
+
+	newGauge := func(name, help string) prometheus.Gauge {
+		g := prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "btfs",
+			Subsystem: "analytics",
+			Name:      name,
+			Help:      help,
+		})
+		registry.MustRegister(g)
+		return g
+	}
+
+	return &prometheusSink{
+		registry:    registry,
+		upTime:      newGauge("up_time_seconds", "Seconds since the daemon started."),
+		storageUsed: newGauge("storage_used_kilobytes", "Repo storage used, in kilobytes."),
+		memUsed:     newGauge("memory_used_kilobytes", "Heap memory in use, in kilobytes."),
+		cpuUsed:     newGauge("cpu_used_percent", "Overall CPU utilization."),
+		upload:      newGauge("upload_kilobytes", "Bitswap data sent since the last heartbeat, in kilobytes."),
+		download:    newGauge("download_kilobytes", "Bitswap data received since the last heartbeat, in kilobytes."),
+		blocksUp:    newGauge("blocks_up_total", "Total blocks uploaded."),
+		blocksDown:  newGauge("blocks_down_total", "Total blocks downloaded."),
+		exchanges:   newGauge("exchanges", "Bitswap exchanges since the last heartbeat."),
+		numPeers:    newGauge("peers_connected", "Number of bitswap peers currently connected."),
+
+		peerLatencyP50: newGauge("peer_latency_p50_ms", "Median per-peer exchange latency, across all tracked peers."),
+		peerLatencyP90: newGauge("peer_latency_p90_ms", "90th percentile per-peer exchange latency, across all tracked peers."),
+		peerLatencyP99: newGauge("peer_latency_p99_ms", "99th percentile per-peer exchange latency, across all tracked peers."),
+		peerSpeedP50:   newGauge("peer_speed_p50_kbs", "Median per-peer transfer speed, across all tracked peers."),
+		peerSpeedP90:   newGauge("peer_speed_p90_kbs", "90th percentile per-peer transfer speed, across all tracked peers."),
+		peerSpeedP99:   newGauge("peer_speed_p99_kbs", "99th percentile per-peer transfer speed, across all tracked peers."),
+	}
+}
+
+func (p *prometheusSink) Name() string { return "prometheus" }
+
+func (p *prometheusSink) Send(dc *dataCollection) error {
+	p.upTime.Set(float64(dc.UpTime))
+	p.storageUsed.Set(float64(dc.StorageUsed))
+	p.memUsed.Set(float64(dc.MemUsed))
+	p.cpuUsed.Set(dc.CPUUsed)
+	p.upload.Set(float64(dc.Upload))
+	p.download.Set(float64(dc.Download))
+	p.blocksUp.Set(float64(dc.BlocksUp))
+	p.blocksDown.Set(float64(dc.BlocksDown))
+	p.exchanges.Set(float64(dc.Exchanges))
+	p.numPeers.Set(float64(dc.NumPeers))
+	p.peerLatencyP50.Set(dc.PeerLatencyP50)
+	p.peerLatencyP90.Set(dc.PeerLatencyP90)
+	p.peerLatencyP99.Set(dc.PeerLatencyP99)
+	p.peerSpeedP50.Set(dc.PeerSpeedP50)
+	p.peerSpeedP90.Set(dc.PeerSpeedP90)
+	p.peerSpeedP99.Set(dc.PeerSpeedP99)
+	return nil
+}
+
+func (p *prometheusSink) Close() error { return nil }
+
+// Handler returns the http.Handler that should be mounted on the node's API
+// mux (e.g. at /metrics) so an external Prometheus server can scrape it.
+func (p *prometheusSink) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
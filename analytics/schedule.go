@@ -0,0 +1,44 @@
+package analytics
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jitterFraction bounds how far a scheduled interval can wander from its
+// base value, so that many nodes started around the same time don't all
+// hit the collector in lockstep.
+const jitterFraction = 0.1
+
+// maxBackoff caps how long collectionAgent will wait after repeated
+// send failures, so a long-dead collector doesn't silently stretch the
+// interval out to infinity.
+const maxBackoff = time.Hour
+
+func jitter(base time.Duration) time.Duration {
+	if base <= 0 {
+		return base
+	}
+	delta := time.Duration(float64(base) * jitterFraction)
+	if delta <= 0 {
+		return base
+	}
+	// +/- jitterFraction around base, uniformly distributed.
+	return base - delta + time.Duration(rand.Int63n(int64(2*delta)))
+}
+
+// nextBackoff doubles the wait on each consecutive failure, capped at
+// maxBackoff, and resets to the jittered base interval on success.
+func nextBackoff(base time.Duration, consecutiveFailures int) time.Duration {
+	if consecutiveFailures <= 0 {
+		return jitter(base)
+	}
+	backoff := base
+	for i := 0; i < consecutiveFailures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
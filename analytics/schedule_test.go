@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterStaysWithinBounds(t *testing.T) {
+	base := 10 * time.Minute
+	low := base - time.Duration(float64(base)*jitterFraction)
+	high := base + time.Duration(float64(base)*jitterFraction)
+
+	for i := 0; i < 1000; i++ {
+		got := jitter(base)
+		if got < low || got > high {
+			t.Fatalf("jitter(%v) = %v, want within [%v, %v]", base, got, low, high)
+		}
+	}
+}
+
+func TestJitterZeroBase(t *testing.T) {
+	if got := jitter(0); got != 0 {
+		t.Fatalf("jitter(0) = %v, want 0", got)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	base := 1 * time.Minute
+
+	if got := nextBackoff(base, 0); got < base-time.Duration(float64(base)*jitterFraction) ||
+		got > base+time.Duration(float64(base)*jitterFraction) {
+		t.Fatalf("nextBackoff(base, 0) = %v, want within jitter of %v", got, base)
+	}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{3, 8 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := nextBackoff(base, c.failures); got != c.want {
+			t.Errorf("nextBackoff(base, %d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestNextBackoffCapsAtMaxBackoff(t *testing.T) {
+	got := nextBackoff(1*time.Minute, 20)
+	if got != maxBackoff {
+		t.Fatalf("nextBackoff with many failures = %v, want capped at %v", got, maxBackoff)
+	}
+}
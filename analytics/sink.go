@@ -0,0 +1,323 @@
+package analytics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	ic "github.com/libp2p/go-libp2p-crypto"
+)
+
+// defaultBatchSize is how many spooled heartbeats the HTTP sink uploads per
+// request once it has a backlog.
+const defaultBatchSize = 20
+
+// MetricsSink is anything dataCollection can report a heartbeat to. Multiple
+// sinks can be active at once (e.g. the legacy HTTP collector alongside a
+// local Prometheus scrape endpoint) so operators can plug BTFS into
+// whatever observability pipeline they already run.
+type MetricsSink interface {
+	// Name identifies the sink in logs and config validation errors.
+	Name() string
+	// Send delivers a single heartbeat. Implementations should not block
+	// longer than their own configured timeout; collectionAgent calls this
+	// synchronously once per heartbeat.
+	Send(dc *dataCollection) error
+	// Close releases any resources (open files, HTTP clients, exporters).
+	Close() error
+}
+
+// SinkConfig describes a single configured sink entry from the BTFS config
+// file's Experimental.Analytics.Sinks array.
+type SinkConfig struct {
+	Type    string            `json:"type"` // "http", "prometheus", "otlp", "log"
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	TLSInsecureSkipVerify bool `json:"tls_insecure_skip_verify,omitempty"`
+
+	// SampleInterval overrides the global heartbeat for this sink only. For
+	// the http sink it paces the upload-batch cadence (Send always spools
+	// immediately); zero makes it upload every heartBeat (the fixed base
+	// interval, not the adaptive one). For every other sink type it gates
+	// Send itself: calls arriving sooner than SampleInterval after the last
+	// forwarded one are dropped; zero forwards every heartbeat.
+	SampleInterval time.Duration `json:"sample_interval,omitempty"`
+
+	// Path is used by the log sink to pick the output file.
+	Path string `json:"path,omitempty"`
+}
+
+// BuildSinks turns the config entries into live sinks, skipping (and
+// logging) any entry with an unrecognized type rather than failing the
+// whole daemon over a typo in the config file. repoPath is where push-style
+// sinks (currently just http) keep their upload spool, and privKey signs
+// each batch so the collector can reject spoofed submissions.
+func BuildSinks(cfgs []SinkConfig, repoPath string, privKey ic.PrivKey) []MetricsSink {
+	sinks := make([]MetricsSink, 0, len(cfgs))
+	for i, c := range cfgs {
+		sink, err := newSink(c, repoPath, privKey, i)
+		if err != nil {
+			fmt.Println("analytics: skipping sink:", err)
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+func newSink(c SinkConfig, repoPath string, privKey ic.PrivKey, idx int) (MetricsSink, error) {
+	switch c.Type {
+	case "", "http":
+		// The http sink reads c.SampleInterval itself to pace uploads; it
+		// must not also be interval-gated here or batches would be paced
+		// twice.
+		return newHTTPSink(c, repoPath, privKey, idx)
+	case "log":
+		sink, err := newLogSink(c)
+		if err != nil {
+			return nil, err
+		}
+		return gateInterval(sink, c.SampleInterval), nil
+	case "prometheus":
+		return gateInterval(newPrometheusSink(c), c.SampleInterval), nil
+	case "otlp":
+		sink, err := newOTLPSink(c)
+		if err != nil {
+			return nil, err
+		}
+		return gateInterval(sink, c.SampleInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", c.Type)
+	}
+}
+
+// intervalGatedSink drops Send calls that arrive sooner than interval after
+// the last one it forwarded, so a configured SampleInterval is honored by
+// sinks that otherwise have no natural batching point of their own.
+type intervalGatedSink struct {
+	MetricsSink
+	interval time.Duration
+
+	mu       sync.Mutex
+	lastSent time.Time
+}
+
+func gateInterval(sink MetricsSink, interval time.Duration) MetricsSink {
+	if interval <= 0 {
+		return sink
+	}
+	return &intervalGatedSink{MetricsSink: sink, interval: interval}
+}
+
+// Unwrap returns the sink intervalGatedSink wraps, so code that needs to
+// reach a concrete sink type (e.g. registerSinkHandlers looking for a
+// *prometheusSink) isn't fooled by the gate sitting in front of it.
+func (g *intervalGatedSink) Unwrap() MetricsSink { return g.MetricsSink }
+
+func (g *intervalGatedSink) Send(dc *dataCollection) error {
+	g.mu.Lock()
+	now := time.Now()
+	if !g.lastSent.IsZero() && now.Sub(g.lastSent) < g.interval {
+		g.mu.Unlock()
+		return nil
+	}
+	g.lastSent = now
+	g.mu.Unlock()
+
+	return g.MetricsSink.Send(dc)
+}
+
+// httpSink delivers heartbeats to the collector in signed, gzip-compressed
+// batches rather than one synchronous POST per heartbeat. Send only appends
+// to a durable on-disk spool; a background loop drains it, so a daemon
+// restart or a flaky link never drops a sample - entries stay spooled until
+// the collector ACKs the batch.
+type httpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	spool   *spool
+	privKey ic.PrivKey
+	stopCh  chan struct{}
+}
+
+func newHTTPSink(c SinkConfig, repoPath string, privKey ic.PrivKey, idx int) (*httpSink, error) {
+	url := c.URL
+	if url == "" {
+		url = dataServeURL
+	}
+	transport := &http.Transport{}
+	if c.TLSInsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	sp, err := newSpool(repoPath, fmt.Sprintf("http-%d", idx))
+	if err != nil {
+		return nil, err
+	}
+
+	interval := c.SampleInterval
+	if interval <= 0 {
+		interval = heartBeat
+	}
+
+	h := &httpSink{
+		url:     url,
+		headers: c.Headers,
+		client:  &http.Client{Transport: transport},
+		spool:   sp,
+		privKey: privKey,
+		stopCh:  make(chan struct{}),
+	}
+	go h.flushLoop(interval)
+	return h, nil
+}
+
+func (h *httpSink) Name() string { return "http:" + h.url }
+
+func (h *httpSink) Send(dc *dataCollection) error {
+	body, err := json.Marshal(dc)
+	if err != nil {
+		return err
+	}
+	return h.spool.Append(body)
+}
+
+// flushLoop periodically drains the spool in batches of defaultBatchSize,
+// backing off exponentially (capped at maxBackoff) while uploads keep
+// failing so a dead collector doesn't get hammered.
+func (h *httpSink) flushLoop(interval time.Duration) {
+	failures := 0
+	for {
+		select {
+		case <-h.stopCh:
+			return
+		case <-time.After(nextBackoff(interval, failures)):
+		}
+
+		n, err := h.flush()
+		if err != nil {
+			fmt.Println("analytics: http sink flush failed:", err)
+			failures++
+			continue
+		}
+		failures = 0
+		_ = n
+	}
+}
+
+// flush uploads up to one batch and, only once the collector has ACKed it,
+// commits the spool so the batch isn't retried. On any failure the spool is
+// left untouched for the next attempt.
+func (h *httpSink) flush() (int, error) {
+	records, err := h.spool.Take(defaultBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	raw := make([]json.RawMessage, len(records))
+	for i, r := range records {
+		raw[i] = r
+	}
+	payload, err := json.Marshal(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(payload); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", h.url, &buf)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Encoding", "gzip")
+	if h.privKey != nil {
+		sig, err := h.privKey.Sign(payload)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Add("X-Btfs-Signature", base64.StdEncoding.EncodeToString(sig))
+	}
+	for k, v := range h.headers {
+		req.Header.Add(k, v)
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return 0, fmt.Errorf("http sink: server returned %s", res.Status)
+	}
+
+	if err := h.spool.Commit(len(records)); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
+
+func (h *httpSink) Close() error {
+	close(h.stopCh)
+	return nil
+}
+
+// logSink appends each heartbeat as a JSON line to a local file, useful for
+// operators who just want to tail/ship logs rather than run a collector.
+type logSink struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+func newLogSink(c SinkConfig) (*logSink, error) {
+	path := c.Path
+	if path == "" {
+		path = "analytics.log"
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &logSink{path: path, f: f}, nil
+}
+
+func (l *logSink) Name() string { return "log:" + l.path }
+
+func (l *logSink) Send(dc *dataCollection) error {
+	body, err := json.Marshal(dc)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.f.Write(append(body, '\n'))
+	return err
+}
+
+func (l *logSink) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// spool is a durable, append-only queue of pending JSON heartbeat records,
+// used so a sink can batch uploads and survive daemon restarts or a flaky
+// link without losing samples. Each call to Append writes one JSON line;
+// Take reads back up to n of the oldest lines without removing them, and
+// the caller calls Commit once the batch has been acknowledged.
+type spool struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newSpool(repoPath, name string) (*spool, error) {
+	dir := filepath.Join(repoPath, "analytics-spool")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &spool{path: filepath.Join(dir, name+".jsonl")}, nil
+}
+
+// Append adds one record to the end of the spool.
+func (s *spool) Append(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(record, '\n'))
+	return err
+}
+
+// Take returns up to n of the oldest pending records without removing them
+// from disk - the caller must call Commit(len(records)) once they've been
+// durably accepted downstream.
+func (s *spool) Take(n int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([][]byte, 0, n)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() && len(records) < n {
+		line := make([]byte, len(scanner.Bytes()))
+		copy(line, scanner.Bytes())
+		records = append(records, line)
+	}
+	return records, scanner.Err()
+}
+
+// Commit removes the n oldest records from the spool after they've been
+// successfully delivered. It rewrites the file, which is fine at the scale
+// a single node's heartbeat spool ever reaches.
+func (s *spool) Commit(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	remaining := make([][]byte, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	i := 0
+	for scanner.Scan() {
+		if i >= n {
+			line := make([]byte, len(scanner.Bytes()))
+			copy(line, scanner.Bytes())
+			remaining = append(remaining, line)
+		}
+		i++
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	for _, line := range remaining {
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
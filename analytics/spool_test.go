@@ -0,0 +1,86 @@
+package analytics
+
+import "testing"
+
+func TestSpoolAppendTakeCommit(t *testing.T) {
+	dir := t.TempDir()
+	sp, err := newSpool(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := [][]byte{[]byte(`{"n":1}`), []byte(`{"n":2}`), []byte(`{"n":3}`)}
+	for _, r := range records {
+		if err := sp.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := sp.Take(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || string(got[0]) != string(records[0]) || string(got[1]) != string(records[1]) {
+		t.Fatalf("Take(2) = %q, want the two oldest records", got)
+	}
+
+	// Taking again without committing must return the same records - Take
+	// is read-only until Commit says otherwise.
+	again, err := sp.Take(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 2 || string(again[0]) != string(records[0]) {
+		t.Fatalf("Take without Commit must be idempotent, got %q", again)
+	}
+
+	if err := sp.Commit(2); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := sp.Take(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || string(remaining[0]) != string(records[2]) {
+		t.Fatalf("after Commit(2), Take(10) = %q, want only the third record", remaining)
+	}
+}
+
+func TestSpoolTakeOnMissingFileReturnsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	sp, err := newSpool(dir, "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sp.Take(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Take on empty spool = %v, want none", got)
+	}
+}
+
+func TestSpoolCommitZeroIsNoop(t *testing.T) {
+	dir := t.TempDir()
+	sp, err := newSpool(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Append([]byte(`{"n":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.Commit(0); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sp.Take(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Commit(0) must not remove anything, got %d records", len(got))
+	}
+}